@@ -0,0 +1,155 @@
+package ftp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultAcceptTimeout bounds how long an active-mode transfer waits for the
+// server to connect back to the listening socket before giving up.
+const defaultAcceptTimeout = 30 * time.Second
+
+// SetActiveMode switches the connection between passive (EPSV/PASV, the
+// default) and active (PORT/EPRT) data transfers. Active mode is needed
+// when the server can't accept inbound data connections but the client can.
+func (c *Connection) SetActiveMode(active bool) {
+	c.activeMode = active
+}
+
+// SetPublicIP overrides the address advertised to the server in PORT/EPRT
+// commands. Required when the client sits behind NAT and its local address
+// isn't reachable by the server.
+func (c *Connection) SetPublicIP(ip string) {
+	c.publicIP = ip
+}
+
+// SetActiveAcceptTimeout configures how long an active-mode transfer waits
+// for the server to connect back before giving up. Defaults to
+// defaultAcceptTimeout.
+func (c *Connection) SetActiveAcceptTimeout(timeout time.Duration) {
+	c.acceptTimeout = timeout
+}
+
+// prepareDataConn negotiates the data channel for a transfer and returns a
+// function that completes the connection once the transfer command (RETR,
+// STOR, ...) has been sent to the server. In passive mode this dials the
+// port the server advertised via EPSV/PASV; in active mode it accepts the
+// connection the server opens back to a local listener advertised via
+// PORT/EPRT.
+func (c *Connection) prepareDataConn() (func() (net.Conn, error), error) {
+	if c.activeMode {
+		return c.prepareActiveDataConn()
+	}
+	port, err := c.dataPort()
+	if err != nil {
+		return nil, err
+	}
+	remoteConnectString := net.JoinHostPort(c.hostname, fmt.Sprintf("%d", port))
+	return func() (net.Conn, error) {
+		return c.dialData(remoteConnectString)
+	}, nil
+}
+
+// prepareActiveDataConn listens locally, tells the server where to connect
+// back via PORT (or EPRT for IPv6), and returns a function that accepts the
+// resulting connection, wrapping it in TLS when the control connection has
+// been secured with AuthTLS or DialTLS. The listener is closed once that
+// connection is accepted (or accepting it times out).
+func (c *Connection) prepareActiveDataConn() (func() (net.Conn, error), error) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return nil, err
+	}
+
+	ip := c.publicIP
+	if ip == "" {
+		localAddr, ok := c.control.LocalAddr().(*net.TCPAddr)
+		if !ok {
+			listener.Close()
+			return nil, fmt.Errorf("FTP Connection Error: could not determine local address for active mode")
+		}
+		ip = localAddr.IP.String()
+	}
+	_, portStr, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	var code uint
+	var response string
+	if strings.Contains(ip, ":") {
+		code, response, err = c.Cmd("EPRT", formatEPRT(ip, port))
+	} else {
+		portArg, fmtErr := formatPORT(ip, port)
+		if fmtErr != nil {
+			listener.Close()
+			return nil, fmtErr
+		}
+		code, response, err = c.Cmd("PORT", portArg)
+	}
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	if err = checkResponseCode(2, code); err != nil {
+		listener.Close()
+		msg := fmt.Sprintf("Cannot set active mode data port. Error: %v. Response: %s", err, response)
+		return nil, fmt.Errorf(msg)
+	}
+
+	return func() (net.Conn, error) {
+		defer listener.Close()
+		timeout := c.acceptTimeout
+		if timeout == 0 {
+			timeout = defaultAcceptTimeout
+		}
+		if tcpListener, ok := listener.(*net.TCPListener); ok {
+			tcpListener.SetDeadline(time.Now().Add(timeout))
+		}
+		conn, err := listener.Accept()
+		if err != nil {
+			return nil, fmt.Errorf("FTP Connection Error: server didn't connect back for active mode transfer. Error: %v", err)
+		}
+		if !c.useTLS {
+			return conn, nil
+		}
+		tlsConn := tls.Client(conn, c.dataTLSConfig())
+		if err = tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("FTP Connection Error: data connection TLS handshake failed. Error: %v", err)
+		}
+		return tlsConn, nil
+	}, nil
+}
+
+// formatPORT builds the h1,h2,h3,h4,p1,p2 argument for the PORT command. ip
+// must parse as an IPv4 address (PORT has no way to represent IPv6; use
+// EPRT for that) - notably, a hostname passed to SetPublicIP won't, so this
+// is reported as an error rather than panicking on the split below.
+func formatPORT(ip string, port int) (string, error) {
+	v4 := net.ParseIP(ip).To4()
+	if v4 == nil {
+		return "", fmt.Errorf("FTP Connection Error: PORT requires a dotted-quad IPv4 address, got %q", ip)
+	}
+	return fmt.Sprintf("%d,%d,%d,%d,%d,%d", v4[0], v4[1], v4[2], v4[3], port/256, port%256), nil
+}
+
+// formatEPRT builds the |proto|address|port| argument for the EPRT command,
+// per RFC 2428 (proto 1 for IPv4, 2 for IPv6).
+func formatEPRT(ip string, port int) string {
+	proto := "1"
+	if strings.Contains(ip, ":") {
+		proto = "2"
+	}
+	return fmt.Sprintf("|%s|%s|%d|", proto, ip, port)
+}
@@ -0,0 +1,40 @@
+package ftp
+
+import "testing"
+
+func TestFormatPORT(t *testing.T) {
+	got, err := formatPORT("127.0.0.1", 52718)
+	if err != nil {
+		t.Fatalf("formatPORT returned error: %v", err)
+	}
+	want := "127,0,0,1,205,238"
+	if got != want {
+		t.Errorf("formatPORT(\"127.0.0.1\", 52718) = %q, want %q", got, want)
+	}
+}
+
+func TestFormatPORTInvalid(t *testing.T) {
+	if _, err := formatPORT("ftp.example.com", 52718); err == nil {
+		t.Error("Should return error for a non-IPv4 address instead of panicking")
+	}
+	if _, err := formatPORT("::1", 52718); err == nil {
+		t.Error("Should return error for an IPv6 address, which PORT can't represent")
+	}
+}
+
+func TestFormatEPRT(t *testing.T) {
+	cases := []struct {
+		ip   string
+		port int
+		want string
+	}{
+		{"127.0.0.1", 52718, "|1|127.0.0.1|52718|"},
+		{"::1", 52718, "|2|::1|52718|"},
+	}
+	for _, c := range cases {
+		got := formatEPRT(c.ip, c.port)
+		if got != c.want {
+			t.Errorf("formatEPRT(%q, %d) = %q, want %q", c.ip, c.port, got, c.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package ftp
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
@@ -15,8 +17,20 @@ import (
 
 // Knows the control connection where commands are sent to the server.
 type Connection struct {
-	control  io.ReadWriteCloser
-	hostname string
+	control          net.Conn
+	hostname         string
+	useTLS           bool
+	tlsConfig        *tls.Config
+	skipEPSV         bool
+	activeMode       bool
+	publicIP         string
+	acceptTimeout    time.Duration
+	featCache        *string
+	debugOutput      io.Writer
+	location         *time.Location
+	ctx              context.Context
+	ctrlReader       *bufio.Reader
+	dataSessionCache tls.ClientSessionCache
 }
 
 var CRLF = "\r\n"
@@ -28,36 +42,23 @@ var IMAGE = "I" //Synonymous with "Binary"
 // host should be in the form of address:port e.g. myserver:21 or myserver:ftp
 // Returns a pointer to a Connection
 func Dial(host string) (*Connection, error) {
-	if host == "" {
-		return nil, fmt.Errorf("FTP Connection Error: Host can not be blank!")
-	}
-	if !hasPort(host) {
-		return nil, fmt.Errorf("FTP Connection Error: Host must have a port! e.g. host:21")
-	}
-	conn, err := net.Dial("tcp", host)
-	if err != nil {
-		return nil, err
-	}
-	// timeoutDuration := 5 * time.Second
-	// conn.setReadDeadline(time.Now().Add(timeoutDuration))
+	return DialContext(context.Background(), host)
+}
 
-	// Upon connect, most servers respond with a welcome message.
-	// The welcome message contains a status code, just like any other command.
-	// TODO: Handle servers with no welcome message.
+// readWelcome reads and validates the banner a server sends immediately
+// after a connection is established, shared by Dial and DialTLS.
+func readWelcome(conn io.Reader) error {
 	welcomeMsg := make([]byte, 1024)
-	_, err = conn.Read(welcomeMsg)
+	_, err := conn.Read(welcomeMsg)
 	if err != nil {
-		return nil, fmt.Errorf("Couldn't read the server's initital connection information. Error: %v", err)
+		return fmt.Errorf("Couldn't read the server's initital connection information. Error: %v", err)
 	}
 	code, err := strconv.Atoi(string(welcomeMsg[0:3]))
 	err = checkResponseCode(2, uint(code))
 	if err != nil {
-		return nil, fmt.Errorf("Couldn't read the server's Welcome Message. Error: %v", err)
+		return fmt.Errorf("Couldn't read the server's Welcome Message. Error: %v", err)
 	}
-	// This doesn't work for IPv6 addresses.
-	hostParts := strings.Split(host, ":")
-	// return &Connection{conn, hostParts[0], conn}, nil
-	return &Connection{conn, hostParts[0]}, nil
+	return nil
 }
 
 // Executes an FTP command.
@@ -68,6 +69,9 @@ func Dial(host string) (*Connection, error) {
 func (c *Connection) Cmd(command string, arg string) (code uint, response string, err error) {
 	// Format command to be sent to the server.
 	formattedCommand := command + " " + arg + CRLF
+	if c.debugOutput != nil {
+		fmt.Fprint(c.debugOutput, "> "+formattedCommand)
+	}
 
 	// Send command to the server.
 	_, err = c.control.Write([]byte(formattedCommand))
@@ -75,11 +79,27 @@ func (c *Connection) Cmd(command string, arg string) (code uint, response string
 		return 0, "", err
 	}
 
-	// Process the response.
-	reader := bufio.NewReader(c.control)
+	code, response, err = c.readReply()
+	if c.debugOutput != nil {
+		fmt.Fprint(c.debugOutput, "< "+response)
+	}
+	return code, response, err
+}
+
+// readReply reads a single (possibly multi-line) reply off the control
+// connection. Used by Cmd to read a command's response, and by DataConn's
+// Close to drain the "226 Transfer complete" reply a server sends once a
+// data connection is torn down. The underlying *bufio.Reader is kept on c so
+// bytes buffered past a reply's terminator (e.g. a pipelined "150"+"226" in
+// one segment) aren't discarded between calls; it must be reset whenever
+// c.control is replaced (see AuthTLS).
+func (c *Connection) readReply() (code uint, response string, err error) {
+	if c.ctrlReader == nil {
+		c.ctrlReader = bufio.NewReader(c.control)
+	}
 	regex := regexp.MustCompile("[0-9][0-9][0-9] ")
 	for {
-		ln, err := reader.ReadString('\n')
+		ln, err := c.ctrlReader.ReadString('\n')
 		if err != nil {
 			return 0, "", err
 		}
@@ -125,89 +145,20 @@ func (c *Connection) Logout() error {
 	return nil
 }
 
-// Configure read deadline to timeout, wrapping
-// net.Conn.setReadDeadline
-// setReadDeadline sets the deadline for future Read calls
-// and any currently-blocked Read call.
-// A zero value for t means Read will not time out.
-func setReadDeadline(c net.Conn, duration uint) {
-	// disable timeout used for test failure
-	timeoutDuration := time.Duration(time.Duration(duration) * time.Second)
-	c.SetReadDeadline(time.Now().Add(timeoutDuration))
-}
-
 // Download a file to a []byte slice and return it
 func (c *Connection) GetBuffer(src, mode string, timeout uint) ([]byte, error) {
-	// Use PASV to set up the data port.
-	pasvCode, pasvLine, err := c.Cmd("PASV", "")
-	if err != nil {
-		return nil, err
-	}
-	pasvErr := checkResponseCode(2, pasvCode)
-	if pasvErr != nil {
-		msg := fmt.Sprintf("Cannot set PASV. Error: %v", pasvErr)
-		return nil, fmt.Errorf(msg)
-	}
-	dataPort, err := extractDataPort(pasvLine)
-	/*_, err = extractDataPort(pasvLine)*/
-	if err != nil {
-		return nil, err
-	}
-
-	// Set the TYPE (ASCII or Binary)
-	typeCode, typeLine, err := c.Cmd("TYPE", mode)
-	if err != nil {
-		return nil, err
-	}
-	typeErr := checkResponseCode(2, typeCode)
-	if typeErr != nil {
-		msg := fmt.Sprintf("Cannot set TYPE. Error: '%v'. Line: '%v'", typeErr, typeLine)
-		return nil, fmt.Errorf(msg)
-	}
-
-	// Can't use Cmd() for RETR because it doesn't return until *after* you've
-	// downloaded the requested file.
-	command := []byte("RETR " + src + CRLF)
-	_, err = c.control.Write(command)
+	stream, err := c.Retr(src, mode)
 	if err != nil {
 		return nil, err
 	}
-
-	// Open connection to remote data port.
-	remoteConnectString := c.hostname + ":" + fmt.Sprintf("%d", dataPort)
-	downloadConn, err := net.Dial("tcp", remoteConnectString)
-	defer downloadConn.Close()
-	if err != nil {
-		msg := fmt.Sprintf("Couldn't connect to server's remote data port. Error: %v", err)
-		return nil, fmt.Errorf(msg)
-	}
-
-	// Buffer for downloading and writing to file
-	bufLen := 1024
-	buf := make([]byte, bufLen)
-	var result bytes.Buffer
-	result.Grow(2 ^ (1024 * 1024))
+	defer stream.Close()
 	if timeout > 0 {
-		setReadDeadline(downloadConn, timeout)
+		stream.SetDeadline(time.Now().Add(time.Duration(timeout) * time.Second))
 	}
 
-	// Read from the server and write the contents to a file
-	for {
-		bytesRead, readErr := downloadConn.Read(buf)
-		if bytesRead > 0 {
-			for i, n := 0, 0; i < bytesRead; i += n {
-				n, readErr = result.Write(buf[0:bytesRead])
-				if err != nil {
-					return nil, readErr
-				}
-			}
-		}
-		if readErr == io.EOF {
-			break
-		}
-		if readErr != nil {
-			return nil, readErr
-		}
+	var result bytes.Buffer
+	if _, err = io.Copy(&result, stream); err != nil {
+		return nil, err
 	}
 	return result.Bytes(), nil
 }
@@ -222,87 +173,31 @@ func (c *Connection) Put(src, dest, mode string, timeout uint) error {
 	return c.UploadFile(src, dest, mode, timeout)
 }
 
-// Download a file from a remote server.  Assumes only passive FTP
-// connections for now. When timeout == 0, ignore, when timeout > 0
-// set timeout limit
+// Download a file from a remote server. Uses passive mode (EPSV/PASV) by
+// default, or active mode (PORT/EPRT) when SetActiveMode(true) has been
+// called. When timeout == 0, ignore, when timeout > 0 set timeout limit
 func (c *Connection) DownloadFile(src, dest, mode string, timeout uint) error {
-	// Use PASV to set up the data port.
-	pasvCode, pasvLine, err := c.Cmd("PASV", "")
-	if err != nil {
-		return err
-	}
-	pasvErr := checkResponseCode(2, pasvCode)
-	if pasvErr != nil {
-		msg := fmt.Sprintf("Cannot set PASV. Error: %v", pasvErr)
-		return fmt.Errorf(msg)
-	}
-	dataPort, err := extractDataPort(pasvLine)
-	/*_, err = extractDataPort(pasvLine)*/
-	if err != nil {
-		return err
-	}
-
-	// Set the TYPE (ASCII or Binary)
-	typeCode, typeLine, err := c.Cmd("TYPE", mode)
-	if err != nil {
-		return err
-	}
-	typeErr := checkResponseCode(2, typeCode)
-	if typeErr != nil {
-		msg := fmt.Sprintf("Cannot set TYPE. Error: '%v'. Line: '%v'", typeErr, typeLine)
-		return fmt.Errorf(msg)
-	}
-
-	// Can't use Cmd() for RETR because it doesn't return until *after* you've
-	// downloaded the requested file.
-	command := []byte("RETR " + src + CRLF)
-	_, err = c.control.Write(command)
+	stream, err := c.Retr(src, mode)
 	if err != nil {
 		return err
 	}
-
-	// Open connection to remote data port.
-	remoteConnectString := c.hostname + ":" + fmt.Sprintf("%d", dataPort)
-	downloadConn, err := net.Dial("tcp", remoteConnectString)
-	defer downloadConn.Close()
-	if err != nil {
-		msg := fmt.Sprintf("Couldn't connect to server's remote data port. Error: %v", err)
-		return fmt.Errorf(msg)
+	defer stream.Close()
+	if timeout > 0 {
+		stream.SetDeadline(time.Now().Add(time.Duration(timeout) * time.Second))
 	}
 
 	// Set up the destination file
 	var filePerms = os.FileMode(0664)
 	destFile, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY, filePerms)
-	defer destFile.Close()
 	if err != nil {
 		msg := fmt.Sprintf("Cannot open destination file, '%s'. %v", dest, err)
 		return fmt.Errorf(msg)
 	}
+	defer destFile.Close()
 
-	// Buffer for downloading and writing to file
-	bufLen := 1024
-	buf := make([]byte, bufLen)
-
-	if timeout > 0 {
-		setReadDeadline(downloadConn, timeout)
-	}
-
-	// Read from the server and write the contents to a file
-	for {
-		bytesRead, readErr := downloadConn.Read(buf)
-		if bytesRead > 0 {
-			_, err := destFile.Write(buf[0:bytesRead])
-			if err != nil {
-				msg := fmt.Sprintf("Coudn't write to file, '%s'. Error: %v", dest, err)
-				return fmt.Errorf(msg)
-			}
-		}
-		if readErr == io.EOF {
-			break
-		}
-		if readErr != nil {
-			return readErr
-		}
+	if _, err = io.Copy(destFile, stream); err != nil {
+		msg := fmt.Sprintf("Coudn't write to file, '%s'. Error: %v", dest, err)
+		return fmt.Errorf(msg)
 	}
 	return nil
 }
@@ -310,80 +205,26 @@ func (c *Connection) DownloadFile(src, dest, mode string, timeout uint) error {
 // Put a file on the ftp server in the location specified by dest. When
 // timeout == 0, ignore, when timeout > 0 set timeout limit
 func (c *Connection) UploadFile(src, dest, mode string, timeout uint) error {
-	// Use PASV to set up the data port.
-	pasvCode, pasvLine, err := c.Cmd("PASV", "")
-	if err != nil {
-		return err
-	}
-	pasvErr := checkResponseCode(2, pasvCode)
-	if pasvErr != nil {
-		msg := fmt.Sprintf("Cannot set PASV. Error: %v", pasvErr)
-		return fmt.Errorf(msg)
-	}
-	dataPort, err := extractDataPort(pasvLine)
-	if err != nil {
-		return err
-	}
-
-	// Set the TYPE (ASCII or Binary)
-	typeCode, typeLine, err := c.Cmd("TYPE", mode)
-	if err != nil {
-		return err
-	}
-	typeErr := checkResponseCode(2, typeCode)
-	if typeErr != nil {
-		msg := fmt.Sprintf("Cannot set TYPE. Error: '%v'. Line: '%v'", typeErr, typeLine)
-		return fmt.Errorf(msg)
-	}
-	// Can't use Cmd() for STOR because it doesn't return until *after* you've
-	// uploaded the requested file.
-	command := []byte("STOR " + dest + CRLF)
-	_, err = c.control.Write(command)
-	if err != nil {
-		return err
-	}
-
-	// Open connection to remote data port.
-	remoteConnectString := c.hostname + ":" + fmt.Sprintf("%d", dataPort)
-	uploadConn, err := net.Dial("tcp", remoteConnectString)
-	defer uploadConn.Close()
-	if err != nil {
-		msg := fmt.Sprintf("Couldn't connect to server's remote data port. Error: %v", err)
-		return fmt.Errorf(msg)
-	}
-
 	// Open the source file for uploading
 	sourceFile, err := os.OpenFile(src, os.O_RDONLY, 0644)
-	defer sourceFile.Close()
 	if err != nil {
 		msg := fmt.Sprintf("Cannot open src file, '%s'. %v", src, err)
 		return fmt.Errorf(msg)
 	}
+	defer sourceFile.Close()
 
-	// Buffer for uploading the file
-	bufLen := 1024
-	buf := make([]byte, bufLen)
-
+	stream, err := c.Stor(dest, mode)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
 	if timeout > 0 {
-		setReadDeadline(uploadConn, timeout)
+		stream.SetDeadline(time.Now().Add(time.Duration(timeout) * time.Second))
 	}
 
-	// Read from the file and write the contents to the server
-	for {
-		bytesRead, readErr := sourceFile.Read(buf)
-		if bytesRead > 0 {
-			_, writeErr := uploadConn.Write(buf[0:bytesRead])
-			if writeErr != nil {
-				msg := fmt.Sprintf("Couldn't write file to server, '%s'. Error: %v", sourceFile.Name(), writeErr)
-				return fmt.Errorf(msg)
-			}
-		}
-		if readErr == io.EOF {
-			break
-		}
-		if readErr != nil {
-			return readErr
-		}
+	if _, err = io.Copy(stream, sourceFile); err != nil {
+		msg := fmt.Sprintf("Couldn't write file to server, '%s'. Error: %v", sourceFile.Name(), err)
+		return fmt.Errorf(msg)
 	}
 	return nil
 }
@@ -0,0 +1,195 @@
+package ftp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// DialOption configures a Connection created by DialContext.
+type DialOption func(*dialOptions)
+
+type dialOptions struct {
+	dialer      *net.Dialer
+	timeout     time.Duration
+	tlsConfig   *tls.Config
+	debugOutput io.Writer
+	location    *time.Location
+}
+
+// WithDialer supplies a custom *net.Dialer (e.g. to bind a local address or
+// set KeepAlive) instead of the zero-value default.
+func WithDialer(dialer *net.Dialer) DialOption {
+	return func(o *dialOptions) { o.dialer = dialer }
+}
+
+// WithTimeout bounds how long dialing the control connection may take.
+func WithTimeout(timeout time.Duration) DialOption {
+	return func(o *dialOptions) { o.timeout = timeout }
+}
+
+// WithTLS dials using implicit FTPS, the same as DialTLS.
+func WithTLS(config *tls.Config) DialOption {
+	return func(o *dialOptions) { o.tlsConfig = config }
+}
+
+// WithDebugOutput writes every command sent and every reply received to w.
+func WithDebugOutput(w io.Writer) DialOption {
+	return func(o *dialOptions) { o.debugOutput = w }
+}
+
+// WithLocation sets the time.Location used to interpret MDTM timestamps,
+// which don't carry their own zone. Defaults to time.UTC.
+func WithLocation(loc *time.Location) DialOption {
+	return func(o *dialOptions) { o.location = loc }
+}
+
+// DialContext connects to a remote FTP server the way Dial does, but honors
+// ctx for cancellation during the dial and accepts DialOptions for a custom
+// dialer, implicit FTPS, debug logging, and MDTM time zone handling.
+func DialContext(ctx context.Context, host string, opts ...DialOption) (*Connection, error) {
+	if host == "" {
+		return nil, fmt.Errorf("FTP Connection Error: Host can not be blank!")
+	}
+	if !hasPort(host) {
+		return nil, fmt.Errorf("FTP Connection Error: Host must have a port! e.g. host:21")
+	}
+
+	options := dialOptions{dialer: &net.Dialer{}, location: time.UTC}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.timeout > 0 {
+		options.dialer.Timeout = options.timeout
+	}
+
+	rawConn, err := options.dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn = rawConn
+	useTLS := options.tlsConfig != nil
+	if useTLS {
+		tlsConn := tls.Client(rawConn, options.tlsConfig)
+		if err = tlsConn.Handshake(); err != nil {
+			return nil, fmt.Errorf("FTP Connection Error: TLS handshake failed. Error: %v", err)
+		}
+		conn = tlsConn
+	}
+
+	// Upon connect, most servers respond with a welcome message.
+	if err = readWelcome(conn); err != nil {
+		return nil, err
+	}
+	hostname, _, err := net.SplitHostPort(host)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Connection{
+		control:     conn,
+		hostname:    hostname,
+		useTLS:      useTLS,
+		tlsConfig:   options.tlsConfig,
+		debugOutput: options.debugOutput,
+		location:    options.location,
+	}
+
+	// Per RFC 4217, the data channel protection level defaults to Clear
+	// until PROT P is sent, even for implicit FTPS; negotiate it now so
+	// data connections opened later are actually encrypted, mirroring
+	// AuthTLS's explicit-mode negotiation.
+	if useTLS {
+		if _, _, err = c.Cmd("PBSZ", "0"); err != nil {
+			return nil, err
+		}
+		if _, _, err = c.Cmd("PROT", "P"); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// watchContext starts a goroutine that forces conn's deadline to now if ctx
+// is cancelled before the returned stop function is called, unblocking
+// whatever Read/Write is in flight on it.
+func watchContext(ctx context.Context, conn net.Conn) (stop func()) {
+	if ctx == nil || ctx.Done() == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// runWithContext watches ctx against the control connection and records it
+// on c so data connections opened by fn are watched too (see
+// openTransfer), for the duration of fn.
+func (c *Connection) runWithContext(ctx context.Context, fn func() error) error {
+	stop := watchContext(ctx, c.control)
+	defer stop()
+	prevCtx := c.ctx
+	c.ctx = ctx
+	defer func() { c.ctx = prevCtx }()
+
+	err := fn()
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// CmdContext is the context-aware variant of Cmd.
+func (c *Connection) CmdContext(ctx context.Context, command, arg string) (uint, string, error) {
+	var code uint
+	var response string
+	err := c.runWithContext(ctx, func() error {
+		var innerErr error
+		code, response, innerErr = c.Cmd(command, arg)
+		return innerErr
+	})
+	return code, response, err
+}
+
+// LoginContext is the context-aware variant of Login.
+func (c *Connection) LoginContext(ctx context.Context, user, password string) error {
+	return c.runWithContext(ctx, func() error {
+		return c.Login(user, password)
+	})
+}
+
+// DownloadFileContext is the context-aware variant of DownloadFile.
+func (c *Connection) DownloadFileContext(ctx context.Context, src, dest, mode string, timeout uint) error {
+	return c.runWithContext(ctx, func() error {
+		return c.DownloadFile(src, dest, mode, timeout)
+	})
+}
+
+// UploadFileContext is the context-aware variant of UploadFile.
+func (c *Connection) UploadFileContext(ctx context.Context, src, dest, mode string, timeout uint) error {
+	return c.runWithContext(ctx, func() error {
+		return c.UploadFile(src, dest, mode, timeout)
+	})
+}
+
+// GetBufferContext is the context-aware variant of GetBuffer.
+func (c *Connection) GetBufferContext(ctx context.Context, src, mode string, timeout uint) ([]byte, error) {
+	var buf []byte
+	err := c.runWithContext(ctx, func() error {
+		var innerErr error
+		buf, innerErr = c.GetBuffer(src, mode, timeout)
+		return innerErr
+	})
+	return buf, err
+}
@@ -0,0 +1,55 @@
+package ftp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// extractEPSVPort interrogates an EPSV server response for the remote port
+// on which to connect, per the RFC 2428 response format:
+// 229 Entering Extended Passive Mode (|||port|)
+func extractEPSVPort(line string) (port uint, err error) {
+	portPattern := `\(\|\|\|([0-9]+)\|\)`
+	re, err := regexp.Compile(portPattern)
+	if err != nil {
+		return 0, err
+	}
+	match := re.FindStringSubmatch(line)
+	if len(match) == 0 {
+		msg := "Cannot find data port in server output: " + line
+		return 0, fmt.Errorf(msg)
+	}
+	p, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, err
+	}
+	return uint(p), nil
+}
+
+// dataPort negotiates a data connection port for a transfer, preferring
+// EPSV (which works for both IPv4 and IPv6 hosts) and falling back to PASV
+// when the server rejects it. Once EPSV is rejected, c.skipEPSV is set so
+// later transfers on the same connection go straight to PASV.
+func (c *Connection) dataPort() (uint, error) {
+	if !c.skipEPSV {
+		epsvCode, epsvLine, err := c.Cmd("EPSV", "")
+		if err != nil {
+			return 0, err
+		}
+		if checkResponseCode(2, epsvCode) == nil {
+			return extractEPSVPort(epsvLine)
+		}
+		c.skipEPSV = true
+	}
+
+	pasvCode, pasvLine, err := c.Cmd("PASV", "")
+	if err != nil {
+		return 0, err
+	}
+	if err = checkResponseCode(2, pasvCode); err != nil {
+		msg := fmt.Sprintf("Cannot set PASV. Error: %v", err)
+		return 0, fmt.Errorf(msg)
+	}
+	return extractDataPort(pasvLine)
+}
@@ -0,0 +1,21 @@
+package ftp
+
+import "testing"
+
+func TestExtractEPSVPort(t *testing.T) {
+	test_string := "229 Entering Extended Passive Mode (|||52718|)"
+	port, err := extractEPSVPort(test_string)
+	if err != nil {
+		t.Error(err)
+	}
+	if port != 52718 {
+		t.Error("Failed port calculation! Expected 52718, got", port)
+	}
+}
+
+func TestExtractEPSVPortNoMatch(t *testing.T) {
+	_, err := extractEPSVPort("500 Command not understood")
+	if err == nil {
+		t.Error("Should return error when the response has no EPSV port")
+	}
+}
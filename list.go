@@ -0,0 +1,270 @@
+package ftp
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EntryType classifies an Entry returned by List.
+type EntryType int
+
+const (
+	EntryTypeFile EntryType = iota
+	EntryTypeFolder
+	EntryTypeLink
+)
+
+// Entry is a single file, folder, or symlink reported by List.
+type Entry struct {
+	Name string
+	Type EntryType
+	Size uint64
+	Time time.Time
+	// Target is the link destination, populated only when Type == EntryTypeLink.
+	Target string
+}
+
+// List returns the directory listing for path. It prefers the structured
+// MLSD command when the server advertises support for it in FEAT, and falls
+// back to parsing Unix ls -l style LIST output otherwise.
+func (c *Connection) List(path string) ([]*Entry, error) {
+	feat, err := c.feat()
+	if err != nil {
+		return nil, err
+	}
+	if supportsFeature(feat, "MLSD") {
+		data, err := c.fetchListing("MLSD", path)
+		if err != nil {
+			return nil, err
+		}
+		return parseMLSD(data)
+	}
+	data, err := c.fetchListing("LIST", path)
+	if err != nil {
+		return nil, err
+	}
+	return parseLIST(data)
+}
+
+// NameList returns the bare file names in path, using NLST.
+func (c *Connection) NameList(path string) ([]string, error) {
+	data, err := c.fetchListing("NLST", path)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range strings.Split(data, CRLF) {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
+
+// feat returns the server's FEAT response, caching it on the connection so
+// repeated List/Hash calls don't re-negotiate it.
+func (c *Connection) feat() (string, error) {
+	if c.featCache != nil {
+		return *c.featCache, nil
+	}
+	code, response, err := c.Cmd("FEAT", "")
+	if err != nil {
+		return "", err
+	}
+	if checkResponseCode(2, code) != nil {
+		// Server doesn't support FEAT; treat it as advertising nothing.
+		response = ""
+	}
+	c.featCache = &response
+	return response, nil
+}
+
+// supportsFeature reports whether name appears as the first word of a line
+// (ignoring case and surrounding whitespace) in a FEAT response, so a
+// feature line carrying arguments (e.g. "HASH SHA-256;MD5") also counts.
+func supportsFeature(feat, name string) bool {
+	for _, line := range strings.Split(feat, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && strings.EqualFold(fields[0], name) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchListing sets ASCII mode, issues a directory listing command
+// (LIST/MLSD/NLST) over the data connection, and returns its raw contents.
+func (c *Connection) fetchListing(command, path string) (string, error) {
+	connectData, err := c.prepareDataConn()
+	if err != nil {
+		return "", err
+	}
+
+	typeCode, typeLine, err := c.Cmd("TYPE", ASCII)
+	if err != nil {
+		return "", err
+	}
+	if err = checkResponseCode(2, typeCode); err != nil {
+		msg := fmt.Sprintf("Cannot set TYPE. Error: '%v'. Line: '%v'", err, typeLine)
+		return "", fmt.Errorf(msg)
+	}
+
+	cmdLine := command
+	if path != "" {
+		cmdLine += " " + path
+	}
+	if err = c.sendTransferCommand(cmdLine); err != nil {
+		return "", err
+	}
+
+	conn, err := connectData()
+	if err != nil {
+		msg := fmt.Sprintf("Couldn't connect to server's remote data port. Error: %v", err)
+		return "", fmt.Errorf(msg)
+	}
+	stream := &DataConn{Conn: conn, ctrl: c}
+	defer stream.Close()
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// parseMLSD parses the facts syntax MLSD uses for each line:
+// type=file;size=1234;modify=20240101T120000; name
+func parseMLSD(data string) ([]*Entry, error) {
+	var entries []*Entry
+	for _, line := range strings.Split(data, CRLF) {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		entry, err := parseMLSDLine(line)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func parseMLSDLine(line string) (*Entry, error) {
+	idx := strings.Index(line, " ")
+	if idx < 0 {
+		return nil, fmt.Errorf("Cannot parse MLSD line: %s", line)
+	}
+	factsPart, name := line[:idx], line[idx+1:]
+
+	entry := &Entry{Name: name, Type: EntryTypeFile}
+	for _, fact := range strings.Split(factsPart, ";") {
+		if fact == "" {
+			continue
+		}
+		kv := strings.SplitN(fact, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, value := strings.ToLower(kv[0]), kv[1]
+		switch key {
+		case "type":
+			lowerValue := strings.ToLower(value)
+			switch {
+			case lowerValue == "dir" || lowerValue == "cdir" || lowerValue == "pdir":
+				entry.Type = EntryTypeFolder
+			case lowerValue == "file":
+				entry.Type = EntryTypeFile
+			case lowerValue == "link" || strings.HasPrefix(lowerValue, "os.unix=slink"):
+				// RFC 3659 reports symlinks as "type=OS.unix=slink:<target>";
+				// the link target, if any, follows the first colon.
+				entry.Type = EntryTypeLink
+				if idx := strings.Index(value, ":"); idx >= 0 {
+					entry.Target = value[idx+1:]
+				}
+			}
+		case "size":
+			if size, err := strconv.ParseUint(value, 10, 64); err == nil {
+				entry.Size = size
+			}
+		case "modify":
+			if t, err := time.Parse("20060102150405", value); err == nil {
+				entry.Time = t
+			} else if t, err := time.Parse("20060102T150405", value); err == nil {
+				entry.Time = t
+			}
+		}
+	}
+	return entry, nil
+}
+
+// listLineRegex matches a Unix "ls -l" style LIST line, e.g.
+// "drwxr-xr-x 2 user group 4096 Jan 01 12:00 dirname"
+var listLineRegex = regexp.MustCompile(`^([\-dl])\S*\s+\d+\s+\S+\s+\S+\s+(\d+)\s+(\w+\s+\d+\s+[\d:]+)\s+(.+)$`)
+
+// parseLIST parses Unix ls -l style LIST output, used as a fallback when the
+// server doesn't support MLSD.
+func parseLIST(data string) ([]*Entry, error) {
+	var entries []*Entry
+	for _, line := range strings.Split(data, CRLF) {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		entry, err := parseLISTLine(line)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func parseLISTLine(line string) (*Entry, error) {
+	match := listLineRegex.FindStringSubmatch(line)
+	if match == nil {
+		return nil, fmt.Errorf("Cannot parse LIST line: %s", line)
+	}
+
+	entry := &Entry{}
+	switch match[1] {
+	case "d":
+		entry.Type = EntryTypeFolder
+	case "l":
+		entry.Type = EntryTypeLink
+	default:
+		entry.Type = EntryTypeFile
+	}
+	if size, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+		entry.Size = size
+	}
+	if t, err := parseListTime(match[3]); err == nil {
+		entry.Time = t
+	}
+
+	name := match[4]
+	if entry.Type == EntryTypeLink {
+		if idx := strings.Index(name, " -> "); idx >= 0 {
+			entry.Target = name[idx+4:]
+			name = name[:idx]
+		}
+	}
+	entry.Name = name
+	return entry, nil
+}
+
+// parseListTime parses the two date formats Unix "ls -l" uses: "Jan _2
+// 15:04" for recent files (year omitted, implied to be the current year),
+// and "Jan _2 2006" for files older than about six months.
+func parseListTime(s string) (time.Time, error) {
+	if t, err := time.Parse("Jan _2 15:04", s); err == nil {
+		now := time.Now()
+		return time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC), nil
+	}
+	return time.Parse("Jan _2 2006", s)
+}
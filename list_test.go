@@ -0,0 +1,124 @@
+package ftp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMLSDLine(t *testing.T) {
+	cases := []struct {
+		line       string
+		wantName   string
+		wantType   EntryType
+		wantSize   uint64
+		wantTime   time.Time
+		wantTarget string
+	}{
+		{
+			line:     "type=file;size=1234;modify=20240101120000; readme.txt",
+			wantName: "readme.txt",
+			wantType: EntryTypeFile,
+			wantSize: 1234,
+			wantTime: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			line:     "type=dir;modify=20230601000000; sub dir",
+			wantName: "sub dir",
+			wantType: EntryTypeFolder,
+		},
+		{
+			line:       "type=OS.unix=slink:/target.txt;size=9; link.txt",
+			wantName:   "link.txt",
+			wantType:   EntryTypeLink,
+			wantTarget: "/target.txt",
+		},
+		{
+			line:     "type=link; link.txt",
+			wantName: "link.txt",
+			wantType: EntryTypeLink,
+		},
+	}
+	for _, c := range cases {
+		entry, err := parseMLSDLine(c.line)
+		if err != nil {
+			t.Fatalf("parseMLSDLine(%q) returned error: %v", c.line, err)
+		}
+		if entry.Name != c.wantName {
+			t.Errorf("parseMLSDLine(%q).Name = %q, want %q", c.line, entry.Name, c.wantName)
+		}
+		if entry.Type != c.wantType {
+			t.Errorf("parseMLSDLine(%q).Type = %v, want %v", c.line, entry.Type, c.wantType)
+		}
+		if c.wantSize != 0 && entry.Size != c.wantSize {
+			t.Errorf("parseMLSDLine(%q).Size = %d, want %d", c.line, entry.Size, c.wantSize)
+		}
+		if !c.wantTime.IsZero() && !entry.Time.Equal(c.wantTime) {
+			t.Errorf("parseMLSDLine(%q).Time = %v, want %v", c.line, entry.Time, c.wantTime)
+		}
+		if entry.Target != c.wantTarget {
+			t.Errorf("parseMLSDLine(%q).Target = %q, want %q", c.line, entry.Target, c.wantTarget)
+		}
+	}
+}
+
+func TestParseMLSDLineInvalid(t *testing.T) {
+	if _, err := parseMLSDLine("no-space-in-this-line"); err == nil {
+		t.Error("Should return error when the line has no name separator")
+	}
+}
+
+func TestParseLISTLine(t *testing.T) {
+	entry, err := parseLISTLine("-rw-r--r-- 1 user group 4096 Jan 01 12:00 readme.txt")
+	if err != nil {
+		t.Fatalf("parseLISTLine returned error: %v", err)
+	}
+	if entry.Type != EntryTypeFile {
+		t.Errorf("Type = %v, want EntryTypeFile", entry.Type)
+	}
+	if entry.Size != 4096 {
+		t.Errorf("Size = %d, want 4096", entry.Size)
+	}
+	if entry.Name != "readme.txt" {
+		t.Errorf("Name = %q, want %q", entry.Name, "readme.txt")
+	}
+}
+
+func TestParseLISTLineSymlink(t *testing.T) {
+	entry, err := parseLISTLine("lrwxrwxrwx 1 user group 4 Jan 01 12:00 link -> target.txt")
+	if err != nil {
+		t.Fatalf("parseLISTLine returned error: %v", err)
+	}
+	if entry.Type != EntryTypeLink {
+		t.Errorf("Type = %v, want EntryTypeLink", entry.Type)
+	}
+	if entry.Name != "link" {
+		t.Errorf("Name = %q, want %q", entry.Name, "link")
+	}
+	if entry.Target != "target.txt" {
+		t.Errorf("Target = %q, want %q", entry.Target, "target.txt")
+	}
+}
+
+func TestParseLISTLineInvalid(t *testing.T) {
+	if _, err := parseLISTLine("not a listing line"); err == nil {
+		t.Error("Should return error when the line doesn't match the LIST format")
+	}
+}
+
+func TestParseListTime(t *testing.T) {
+	recent, err := parseListTime("Jan  2 15:04")
+	if err != nil {
+		t.Fatalf("parseListTime returned error: %v", err)
+	}
+	if recent.Month() != time.January || recent.Day() != 2 || recent.Hour() != 15 || recent.Minute() != 4 {
+		t.Errorf("parseListTime(\"Jan  2 15:04\") = %v, want Jan 2 15:04", recent)
+	}
+
+	old, err := parseListTime("Jan  2 2019")
+	if err != nil {
+		t.Fatalf("parseListTime returned error: %v", err)
+	}
+	if old.Year() != 2019 || old.Month() != time.January || old.Day() != 2 {
+		t.Errorf("parseListTime(\"Jan  2 2019\") = %v, want Jan 2 2019", old)
+	}
+}
@@ -0,0 +1,197 @@
+package ftp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Size returns the size in bytes of path on the server, via SIZE.
+func (c *Connection) Size(path string) (int64, error) {
+	code, response, err := c.Cmd("SIZE", path)
+	if err != nil {
+		return 0, err
+	}
+	if err = checkResponseCode(2, code); err != nil {
+		msg := fmt.Sprintf("Cannot SIZE '%s'. Error: %v. Response: %s", path, err, response)
+		return 0, fmt.Errorf(msg)
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(response[4:]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Cannot parse SIZE response: %s", response)
+	}
+	return size, nil
+}
+
+// ModTime returns the last modification time of path on the server, via
+// MDTM. The timestamp is interpreted in the Connection's location (UTC
+// unless WithLocation was passed to DialContext).
+func (c *Connection) ModTime(path string) (time.Time, error) {
+	code, response, err := c.Cmd("MDTM", path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err = checkResponseCode(2, code); err != nil {
+		msg := fmt.Sprintf("Cannot MDTM '%s'. Error: %v. Response: %s", path, err, response)
+		return time.Time{}, fmt.Errorf(msg)
+	}
+	loc := c.location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t, err := parseMDTM(response[4:], loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Cannot parse MDTM response: %s", response)
+	}
+	return t, nil
+}
+
+// parseMDTM parses the timestamp argument of an MDTM 213 reply, interpreting
+// it in loc. RFC 3659 allows an optional ".sss" fractional-seconds suffix.
+func parseMDTM(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	layout := "20060102150405"
+	if strings.Contains(value, ".") {
+		layout += ".999"
+	}
+	return time.ParseInLocation(layout, value, loc)
+}
+
+// Hash returns the digest of path computed server-side, letting callers
+// verify integrity after a resumed transfer. It issues the modern HASH
+// command when FEAT advertises it, falling back to the legacy XCRC/XMD5/
+// XSHA1 extensions for algo values "CRC32", "MD5", and "SHA1" respectively.
+func (c *Connection) Hash(path, algo string) ([]byte, error) {
+	feat, err := c.feat()
+	if err != nil {
+		return nil, err
+	}
+
+	var code uint
+	var response string
+	haveHash := false
+	if supportsFeature(feat, "HASH") {
+		if optsCode, _, optsErr := c.Cmd("OPTS", "HASH "+algo); optsErr == nil && checkResponseCode(2, optsCode) == nil {
+			haveHash = true
+		}
+	}
+
+	switch {
+	case haveHash:
+		code, response, err = c.Cmd("HASH", path)
+	case algo == "CRC32" && supportsFeature(feat, "XCRC"):
+		code, response, err = c.Cmd("XCRC", path)
+	case algo == "MD5" && supportsFeature(feat, "XMD5"):
+		code, response, err = c.Cmd("XMD5", path)
+	case algo == "SHA1" && supportsFeature(feat, "XSHA1"):
+		code, response, err = c.Cmd("XSHA1", path)
+	default:
+		return nil, fmt.Errorf("FTP Connection Error: server doesn't advertise %s hashing support", algo)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = checkResponseCode(2, code); err != nil {
+		msg := fmt.Sprintf("Cannot hash '%s'. Error: %v. Response: %s", path, err, response)
+		return nil, fmt.Errorf(msg)
+	}
+
+	value := strings.TrimSpace(response[4:])
+	var digest string
+	if haveHash {
+		// The HASH reply is "<hashname> <start>-<end> <hashvalue> [<filename>]";
+		// take the hashvalue field by position, since a server that appends
+		// the filename would otherwise have it mistaken for the digest.
+		fields := strings.Fields(value)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("Cannot parse HASH response: %s", response)
+		}
+		digest = fields[2]
+	} else {
+		// Legacy XCRC/XMD5/XSHA1 replies are just "<hexdigest>", optionally
+		// preceded by the path the server echoes back.
+		digest = value
+		if idx := strings.LastIndex(digest, " "); idx >= 0 {
+			digest = digest[idx+1:]
+		}
+	}
+	return hex.DecodeString(digest)
+}
+
+// ResumeDownload continues a previously interrupted download of src into
+// dest: it stats dest for the already-downloaded size, sends REST <offset>,
+// then RETR and appends the remaining bytes. If dest doesn't exist yet, it
+// behaves just like DownloadFile.
+func (c *Connection) ResumeDownload(src, dest, mode string) error {
+	var offset int64
+	if info, err := os.Stat(dest); err == nil {
+		offset = info.Size()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	destFile, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.FileMode(0664))
+	if err != nil {
+		msg := fmt.Sprintf("Cannot open destination file, '%s'. %v", dest, err)
+		return fmt.Errorf(msg)
+	}
+	defer destFile.Close()
+
+	conn, stop, err := c.openTransfer("RETR", src, mode, offset)
+	if err != nil {
+		return err
+	}
+	stream := &DataConn{Conn: conn, ctrl: c, stop: stop}
+	defer stream.Close()
+
+	if _, err = io.Copy(destFile, stream); err != nil {
+		msg := fmt.Sprintf("Coudn't write to file, '%s'. Error: %v", dest, err)
+		return fmt.Errorf(msg)
+	}
+	return nil
+}
+
+// ResumeUpload continues a previously interrupted upload of src to dest: it
+// SIZEs the remote file for the already-uploaded size, sends REST <offset>,
+// then STOR and appends the remaining bytes. If dest doesn't exist
+// remotely yet, it behaves just like UploadFile.
+func (c *Connection) ResumeUpload(src, dest, mode string) error {
+	var offset int64
+	if size, err := c.Size(dest); err == nil {
+		offset = size
+	}
+
+	sourceFile, err := os.OpenFile(src, os.O_RDONLY, 0644)
+	if err != nil {
+		msg := fmt.Sprintf("Cannot open src file, '%s'. %v", src, err)
+		return fmt.Errorf(msg)
+	}
+	defer sourceFile.Close()
+
+	if info, statErr := sourceFile.Stat(); statErr == nil && offset > info.Size() {
+		return fmt.Errorf("FTP Connection Error: remote file '%s' (%d bytes) is larger than local source '%s' (%d bytes)", dest, offset, src, info.Size())
+	}
+
+	if offset > 0 {
+		if _, err = sourceFile.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	conn, stop, err := c.openTransfer("STOR", dest, mode, offset)
+	if err != nil {
+		return err
+	}
+	stream := &DataConn{Conn: conn, ctrl: c, stop: stop}
+	defer stream.Close()
+
+	if _, err = io.Copy(stream, sourceFile); err != nil {
+		msg := fmt.Sprintf("Couldn't write file to server, '%s'. Error: %v", sourceFile.Name(), err)
+		return fmt.Errorf(msg)
+	}
+	return nil
+}
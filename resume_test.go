@@ -0,0 +1,34 @@
+package ftp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMDTM(t *testing.T) {
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	got, err := parseMDTM(" 20240102030405", time.UTC)
+	if err != nil {
+		t.Fatalf("parseMDTM returned error: %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("parseMDTM(\"20240102030405\") = %v, want %v", got, want)
+	}
+}
+
+func TestParseMDTMFractionalSeconds(t *testing.T) {
+	got, err := parseMDTM("20240102030405.678", time.UTC)
+	if err != nil {
+		t.Fatalf("parseMDTM returned error: %v", err)
+	}
+	want := time.Date(2024, 1, 2, 3, 4, 5, 678000000, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("parseMDTM(\"20240102030405.678\") = %v, want %v", got, want)
+	}
+}
+
+func TestParseMDTMInvalid(t *testing.T) {
+	if _, err := parseMDTM("not-a-timestamp", time.UTC); err == nil {
+		t.Error("Should return error for a malformed MDTM timestamp")
+	}
+}
@@ -0,0 +1,122 @@
+package ftp
+
+import (
+	"fmt"
+	"net"
+)
+
+// DataConn wraps an FTP data connection. It satisfies both io.ReadCloser and
+// io.WriteCloser, and exposes SetDeadline so callers can bound an individual
+// transfer, mirroring jlaffaye/ftp's Response type. Closing it closes the
+// data connection and drains the trailing "226 Transfer complete" reply off
+// the control connection.
+type DataConn struct {
+	net.Conn
+	ctrl *Connection
+	stop func()
+}
+
+// Close closes the data connection and reads the reply the server sends on
+// the control connection once the transfer is done.
+func (d *DataConn) Close() error {
+	if d.stop != nil {
+		d.stop()
+	}
+	err := d.Conn.Close()
+	if _, _, replyErr := d.ctrl.readReply(); replyErr != nil && err == nil {
+		err = replyErr
+	}
+	return err
+}
+
+// Retr opens a streaming download of path and returns a *DataConn over the
+// data connection, so callers can io.Copy it anywhere without buffering the
+// whole file in memory or a temp path, and can call SetDeadline to bound the
+// transfer. Closing the returned stream drains the trailing "226 Transfer
+// complete" reply.
+func (c *Connection) Retr(path, mode string) (*DataConn, error) {
+	conn, stop, err := c.openTransfer("RETR", path, mode, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &DataConn{Conn: conn, ctrl: c, stop: stop}, nil
+}
+
+// Stor opens a streaming upload to path and returns a *DataConn over the
+// data connection, so callers can call SetDeadline to bound the transfer.
+// Closing the returned stream drains the trailing "226 Transfer complete"
+// reply.
+func (c *Connection) Stor(path, mode string) (*DataConn, error) {
+	conn, stop, err := c.openTransfer("STOR", path, mode, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &DataConn{Conn: conn, ctrl: c, stop: stop}, nil
+}
+
+// openTransfer negotiates the data channel, sets the transfer TYPE, sends
+// REST when offset is non-zero, sends command (RETR or STOR), and returns
+// the resulting data connection along with a stop function that ends the
+// context watcher started on it. It is the shared core of Retr, Stor, and
+// the Resume* transfers.
+func (c *Connection) openTransfer(command, path, mode string, offset int64) (net.Conn, func(), error) {
+	connectData, err := c.prepareDataConn()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	typeCode, typeLine, err := c.Cmd("TYPE", mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = checkResponseCode(2, typeCode); err != nil {
+		msg := fmt.Sprintf("Cannot set TYPE. Error: '%v'. Line: '%v'", err, typeLine)
+		return nil, nil, fmt.Errorf(msg)
+	}
+
+	if offset > 0 {
+		restCode, restLine, err := c.Cmd("REST", fmt.Sprintf("%d", offset))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = checkResponseCode(3, restCode); err != nil {
+			msg := fmt.Sprintf("Cannot REST at offset %d. Error: '%v'. Line: '%v'", offset, err, restLine)
+			return nil, nil, fmt.Errorf(msg)
+		}
+	}
+
+	// Send RETR/STOR with sendTransferCommand, which reads only the
+	// preliminary 1yz reply; the final reply is read when the stream closes.
+	if err = c.sendTransferCommand(command + " " + path); err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := connectData()
+	if err != nil {
+		msg := fmt.Sprintf("Couldn't connect to server's remote data port. Error: %v", err)
+		return nil, nil, fmt.Errorf(msg)
+	}
+	stop := watchContext(c.ctx, conn)
+	return conn, stop, nil
+}
+
+// sendTransferCommand writes a command that precedes a data transfer (RETR,
+// STOR, LIST, MLSD, NLST, ...) to the control connection and reads the
+// preliminary "1yz" reply the server sends before it starts moving data. A
+// command the server rejects outright (e.g. "550 File not found") comes back
+// as a 4yz/5yz reply here instead, which is reported as an error without
+// ever touching the data connection.
+func (c *Connection) sendTransferCommand(command string) error {
+	if _, err := c.control.Write([]byte(command + CRLF)); err != nil {
+		return err
+	}
+	code, response, err := c.readReply()
+	if err != nil {
+		return err
+	}
+	if err = checkResponseCode(1, code); err != nil {
+		msg := fmt.Sprintf("Command '%s' rejected. Error: %v. Response: %s", command, err, response)
+		return fmt.Errorf(msg)
+	}
+	return nil
+}
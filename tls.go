@@ -0,0 +1,81 @@
+package ftp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+)
+
+// DialTLS connects to a remote FTP server that requires implicit FTPS: the
+// control connection is wrapped in TLS before the welcome banner is read.
+// host should be in the form of address:port e.g. myserver:990
+func DialTLS(host string, config *tls.Config) (*Connection, error) {
+	return DialContext(context.Background(), host, WithTLS(config))
+}
+
+// AuthTLS upgrades an already established plaintext control connection to
+// explicit FTPS. It sends AUTH TLS, expects a 234 response, upgrades the
+// control connection with tls.Client, then sends PBSZ 0 and PROT P so that
+// subsequent PASV data connections are also protected using config.
+func (c *Connection) AuthTLS(config *tls.Config) error {
+	code, response, err := c.Cmd("AUTH", "TLS")
+	if err != nil {
+		return err
+	}
+	if err = checkResponseCode(234, code); err != nil {
+		msg := fmt.Sprintf("AUTH TLS rejected by server. Error: %v. Response: %s", err, response)
+		return fmt.Errorf(msg)
+	}
+
+	conn := tls.Client(c.control, config)
+	if err = conn.Handshake(); err != nil {
+		return fmt.Errorf("FTP Connection Error: TLS handshake failed. Error: %v", err)
+	}
+	c.control = conn
+	c.ctrlReader = nil
+	c.tlsConfig = config
+	c.useTLS = true
+
+	if _, _, err = c.Cmd("PBSZ", "0"); err != nil {
+		return err
+	}
+	if _, _, err = c.Cmd("PROT", "P"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// dialData opens a connection to the given remote data port. When the
+// control connection has been secured with AuthTLS or DialTLS, the data
+// connection is wrapped in TLS too, reusing a session cache across data
+// connections so servers that require data-channel session resumption are
+// satisfied.
+func (c *Connection) dialData(remoteConnectString string) (net.Conn, error) {
+	conn, err := net.Dial("tcp", remoteConnectString)
+	if err != nil {
+		return nil, err
+	}
+	if !c.useTLS {
+		return conn, nil
+	}
+	tlsConn := tls.Client(conn, c.dataTLSConfig())
+	if err = tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("FTP Connection Error: data connection TLS handshake failed. Error: %v", err)
+	}
+	return tlsConn, nil
+}
+
+// dataTLSConfig returns the *tls.Config to use for a data connection: a
+// clone of c.tlsConfig carrying c's own session cache, so dialing data
+// connections doesn't mutate the possibly-shared config callers passed to
+// DialTLS/AuthTLS/WithTLS.
+func (c *Connection) dataTLSConfig() *tls.Config {
+	if c.dataSessionCache == nil {
+		c.dataSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+	config := c.tlsConfig.Clone()
+	config.ClientSessionCache = c.dataSessionCache
+	return config
+}